@@ -0,0 +1,106 @@
+package compiler
+
+import (
+	"fmt"
+	"reflect"
+	"text/template/parse"
+
+	"github.com/mh-cbon/template-tree-simplifier/simplifier"
+)
+
+// composeBaseTemplates resolves t.BaseTemplates — the baseof.html-style
+// layout chain declared on compiled.TemplateConfiguration — against every
+// other file already parsed into t.files. Each non-base file is treated as
+// a child that fills in the bases' {{block}} holes with its own
+// {{define}}s, mirroring how html/template resolves a ParseFiles() set
+// shared between a layout and its pages. The result is one merged file per
+// child, registered under the child's own name, so callers keep calling
+// Compiled("list.html") without needing to know about baseof.html.
+func (t *TemplateToCompile) composeBaseTemplates() error {
+	if len(t.BaseTemplates) == 0 {
+		return nil
+	}
+
+	bases := make([]TemplateFileToCompile, 0, len(t.BaseTemplates))
+	for _, baseName := range t.BaseTemplates {
+		base, ok := t.findFile(baseName)
+		if !ok {
+			return fmt.Errorf("BaseTemplates: base template %q was not found among %q", baseName, t.TemplatesPath)
+		}
+		bases = append(bases, base)
+	}
+
+	composed := make([]TemplateFileToCompile, 0, len(t.files))
+	for _, f := range t.files {
+		if t.isBaseTemplate(f.name) {
+			continue // bases are layered into every child below, never emitted standalone
+		}
+		composed = append(composed, mergeWithBases(f, bases))
+	}
+	t.files = composed
+	return nil
+}
+
+func (t *TemplateToCompile) isBaseTemplate(name string) bool {
+	for _, b := range t.BaseTemplates {
+		if b == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *TemplateToCompile) findFile(name string) (TemplateFileToCompile, bool) {
+	for _, f := range t.files {
+		if f.name == name {
+			return f, true
+		}
+	}
+	return TemplateFileToCompile{}, false
+}
+
+// mergeWithBases layers bases (outermost first) under child so the child's
+// own {{define "name"}} wins over a base's {{block "name"}} default body,
+// then aliases child.name to the outermost base's entry point, so the
+// composed page is what gets registered under the child's own name.
+func mergeWithBases(child TemplateFileToCompile, bases []TemplateFileToCompile) TemplateFileToCompile {
+	merged := TemplateFileToCompile{
+		name:             child.name,
+		tplsTree:         map[string]*parse.Tree{},
+		tplsFunc:         map[string]string{},
+		tplsTypeCheck:    map[string]*simplifier.State{},
+		tplsDotType:      map[string]reflect.Type{},
+		definedTemplates: []string{},
+	}
+
+	for _, base := range bases {
+		for name, tree := range base.tplsTree {
+			merged.tplsTree[name] = tree
+			merged.tplsFunc[name] = base.tplsFunc[name]
+			merged.tplsTypeCheck[name] = base.tplsTypeCheck[name]
+			merged.tplsDotType[name] = base.tplsDotType[name]
+		}
+	}
+	for name, tree := range child.tplsTree {
+		if name == child.name {
+			continue // the child file's own flat root just fills layout holes, it isn't a page on its own
+		}
+		merged.tplsTree[name] = tree
+		merged.tplsFunc[name] = child.tplsFunc[name]
+		merged.tplsTypeCheck[name] = child.tplsTypeCheck[name]
+		merged.tplsDotType[name] = child.tplsDotType[name]
+	}
+
+	entryPoint := bases[0].name
+	merged.tplsTree[child.name] = merged.tplsTree[entryPoint]
+	merged.tplsFunc[child.name] = merged.tplsFunc[entryPoint]
+	merged.tplsTypeCheck[child.name] = merged.tplsTypeCheck[entryPoint]
+	merged.tplsDotType[child.name] = merged.tplsDotType[entryPoint]
+
+	for name := range merged.tplsTree {
+		if name != child.name {
+			merged.definedTemplates = append(merged.definedTemplates, name)
+		}
+	}
+	return merged
+}
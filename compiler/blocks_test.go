@@ -0,0 +1,41 @@
+package compiler
+
+import (
+	"testing"
+	"text/template/parse"
+)
+
+func mustParseTree(name, text string, funcs ...map[string]interface{}) *parse.Tree {
+	tree, err := parse.New(name).Parse(text, "", "", map[string]*parse.Tree{}, funcs...)
+	if err != nil {
+		panic(err)
+	}
+	return tree
+}
+
+// TestMergeWithBasesEntryPointIsOutermost guards against regressing to
+// bases[len(bases)-1]: with more than one layer, the composed page must be
+// registered under the outermost base's root tree (bases[0]), matching
+// mergeWithBases' own "outermost first" doc comment.
+func TestMergeWithBasesEntryPointIsOutermost(t *testing.T) {
+	outer := TemplateFileToCompile{
+		name:     "baseof.html",
+		tplsTree: map[string]*parse.Tree{"baseof.html": mustParseTree("baseof.html", "outer")},
+	}
+	inner := TemplateFileToCompile{
+		name:     "section.html",
+		tplsTree: map[string]*parse.Tree{"section.html": mustParseTree("section.html", "inner")},
+	}
+	child := TemplateFileToCompile{
+		name:     "list.html",
+		tplsTree: map[string]*parse.Tree{"list.html": mustParseTree("list.html", "child")},
+	}
+
+	merged := mergeWithBases(child, []TemplateFileToCompile{outer, inner})
+
+	got := merged.tplsTree["list.html"]
+	want := outer.tplsTree["baseof.html"]
+	if got != want {
+		t.Fatalf("expected list.html to be registered against the outermost base's tree, got a different tree")
+	}
+}
@@ -1,14 +1,18 @@
 package compiler
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
 	"fmt"
 	"go/ast"
 	html "html/template"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"sort"
 	"strings"
+	"sync"
 	text "text/template"
 	"text/template/parse"
 
@@ -19,6 +23,7 @@ import (
 
 // CompiledTemplatesProgram ...
 type CompiledTemplatesProgram struct {
+	mu           sync.Mutex
 	varName      string
 	imports      []*ast.ImportSpec
 	funcs        []*ast.FuncDecl
@@ -74,36 +79,99 @@ func (c *CompiledTemplatesProgram) compileTemplates(outpkg string, templatesToCo
 	}
 	return c.generateProgram(outpkg, templatesToCompile), nil
 }
+
+// convertTreeJob is one (file, treeName) unit of AST generation work. Jobs
+// are built and named sequentially so naming stays deterministic, then run
+// on a worker pool; funcDecl is merged back under a mutex, and c.funcs is
+// re-sorted to jobIndex afterwards so the emitted order never depends on
+// which worker finished first.
+type convertTreeJob struct {
+	jobIndex int
+	t        *TemplateToCompile
+	f        TemplateFileToCompile
+	name     string
+}
+
 func (c *CompiledTemplatesProgram) convertTemplates(templatesToCompile []*TemplateToCompile) error {
+	jobs := []convertTreeJob{}
+
+	// Trees that hash identically (same parse structure, resolved against
+	// the same dot type) render identically, so only the first one seen is
+	// scheduled for conversion; every later alias just points its init()
+	// registration at that same generated func name.
+	seenHash := map[string]string{}
+
 	for _, t := range templatesToCompile {
 		for _, f := range t.files {
 			for _, name := range f.names() {
-				f.tplsFunc[name] = c.makeFuncName(f.tplsFunc[name])
-				f.tplsFunc[name] = snakeToCamel(f.tplsFunc[name])
-
-				err := convertTplTree(
-					f.tplsFunc[name],
-					f.tplsTree[name],
-					t.FuncsExport,
-					t.PublicIdents,
-					t.DataConfiguration,
-					f.tplsTypeCheck[name],
-					c,
-				)
-				if err != nil {
-					return err
+				hash := hashTree(f.tplsTree[name], f.tplsDotType[name], t.FuncsExport)
+				if canonicalName, ok := seenHash[hash]; ok {
+					f.tplsFunc[name] = canonicalName
+					continue
 				}
+
+				funcname := c.makeFuncName(f.tplsFunc[name])
+				funcname = snakeToCamel(funcname)
+				f.tplsFunc[name] = funcname
+				seenHash[hash] = funcname
+
+				jobs = append(jobs, convertTreeJob{jobIndex: len(jobs), t: t, f: f, name: name})
 			}
 		}
 	}
+
+	errs := make([]error, len(jobs))
+	order := make([]string, len(jobs))
+	runParallel(len(jobs), func(i int) {
+		job := jobs[i]
+		order[i] = job.f.tplsFunc[job.name]
+		errs[i] = convertTplTree(
+			job.f.tplsFunc[job.name],
+			job.f.tplsTree[job.name],
+			job.t.FuncsExport,
+			job.t.PublicIdents,
+			job.t.DataConfiguration,
+			job.f.tplsTypeCheck[job.name],
+			c,
+		)
+	})
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	c.sortFuncsByName(order)
 	return nil
 }
 
+// sortFuncsByName reorders c.funcs to match order, the deterministic job
+// sequence, undoing whatever order the parallel workers happened to finish
+// (and thus append) in.
+func (c *CompiledTemplatesProgram) sortFuncsByName(order []string) {
+	rank := make(map[string]int, len(order))
+	for i, name := range order {
+		rank[name] = i
+	}
+	sort.SliceStable(c.funcs, func(i, j int) bool {
+		return rank[c.funcs[i].Name.Name] < rank[c.funcs[j].Name.Name]
+	})
+}
+
 // ...
 func (c *CompiledTemplatesProgram) getTemplatesToCompile(conf *compiled.Configuration) ([]*TemplateToCompile, error) {
 	templatesToCompile := convertConfigToTemplatesToCompile(conf)
+	errs := make([]error, len(templatesToCompile))
+	runParallel(len(templatesToCompile), func(i int) {
+		errs[i] = templatesToCompile[i].prepare()
+	})
+	for _, err := range errs {
+		if err != nil {
+			return templatesToCompile, err
+		}
+	}
 	for _, t := range templatesToCompile {
-		if err := t.prepare(); err != nil {
+		if err := t.composeBaseTemplates(); err != nil {
 			return templatesToCompile, err
 		}
 	}
@@ -131,6 +199,8 @@ func (c *CompiledTemplatesProgram) getDataQualifier(dataConf compiled.DataConfig
 }
 
 func (c *CompiledTemplatesProgram) addImport(pkgpath string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	qpath := fmt.Sprintf("%q", pkgpath)
 	bpath := filepath.Base(pkgpath)
 	// if already imported, return the current alias
@@ -171,6 +241,8 @@ func (c *CompiledTemplatesProgram) isCollidingIdent(ident string) bool {
 }
 
 func (c *CompiledTemplatesProgram) makeFuncName(baseName string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	x := baseName
 	i := 0
 	for c.isCollidingIdent(x) {
@@ -189,16 +261,26 @@ func %v(t parse.Templater, w io.Writer, indata interface{}) error {}`,
 	)
 	f := stringToAst(gocode)
 	fn := f.Decls[0].(*ast.FuncDecl)
+	c.mu.Lock()
 	c.funcs = append(c.funcs, fn)
+	c.mu.Unlock()
 	return fn
 }
 
 func (c *CompiledTemplatesProgram) addBuiltintText(text string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	if x, ok := c.builtinTexts[text]; ok {
 		return x
 	}
-	c.builtinTexts[text] = fmt.Sprintf("%v%v", "builtin", len(c.builtinTexts))
-	return c.builtinTexts[text]
+	// Named from a hash of text itself, not an encounter-order counter: the
+	// parallel workers in convertTemplates reach addBuiltintText in a
+	// scheduler-dependent order, and a counter would bake a different name
+	// for the same literal text into different runs' generated Go.
+	sum := sha1.Sum([]byte(text))
+	name := fmt.Sprintf("builtin%v", hex.EncodeToString(sum[:]))
+	c.builtinTexts[text] = name
+	return name
 }
 
 func (c *CompiledTemplatesProgram) generateInitFunc(tpls []*TemplateToCompile) string {
@@ -242,9 +324,20 @@ func (c *CompiledTemplatesProgram) generateProgram(outpkg string, tpls []*Templa
 }
 
 func (c *CompiledTemplatesProgram) generateImportStmt() string {
+	// c.imports is populated from parallel workers, so its append order
+	// isn't stable across runs; sort by import path so the order of the
+	// import lines doesn't depend on which worker discovered each one first.
+	// When two imported paths share a base name, which of them addImport
+	// gives the "alias" prefix still depends on which was added first.
+	imports := make([]*ast.ImportSpec, len(c.imports))
+	copy(imports, c.imports)
+	sort.Slice(imports, func(i, j int) bool {
+		return imports[i].Path.Value < imports[j].Path.Value
+	})
+
 	importStmt := ""
 	importStmt += fmt.Sprintf("import (\n")
-	for _, i := range c.imports {
+	for _, i := range imports {
 		importStmt += fmt.Sprintf("\t")
 		if i.Name != nil {
 			importStmt += fmt.Sprintf("%v ", i.Name.Name)
@@ -256,9 +349,17 @@ func (c *CompiledTemplatesProgram) generateImportStmt() string {
 }
 
 func (c *CompiledTemplatesProgram) generateBuiltins() string {
-	builtins := ""
+	names := make([]string, 0, len(c.builtinTexts))
+	textByName := make(map[string]string, len(c.builtinTexts))
 	for text, name := range c.builtinTexts {
-		builtins += fmt.Sprintf("var %v = []byte(%q)\n", name, text)
+		names = append(names, name)
+		textByName[name] = text
+	}
+	sort.Strings(names)
+
+	builtins := ""
+	for _, name := range names {
+		builtins += fmt.Sprintf("var %v = []byte(%q)\n", name, textByName[name])
 	}
 	return builtins
 }
@@ -285,6 +386,7 @@ type TemplateFileToCompile struct {
 	tplsTree         map[string]*parse.Tree
 	tplsFunc         map[string]string
 	tplsTypeCheck    map[string]*simplifier.State
+	tplsDotType      map[string]reflect.Type
 	definedTemplates []string
 }
 
@@ -310,12 +412,33 @@ func (t *TemplateToCompile) prepare() error {
 	if err != nil {
 		return fmt.Errorf("Failed to glob the templates: %v %v", t.TemplatesPath, err)
 	}
-	for _, tplPath := range tplsPath {
-		fileTpl, err := makeTemplateFileToCompileFromFile(tplPath, t.Data, t.FuncsExport, t.HTML)
+
+	// Parsing and type-checking each file is independent, so it runs on a
+	// worker pool; results are merged back in glob order afterwards so
+	// t.files stays deterministic regardless of which file finished first.
+	files := make([]TemplateFileToCompile, len(tplsPath))
+	fileErrs := make([]error, len(tplsPath))
+	fileTypeErrs := make([][]error, len(tplsPath))
+	runParallel(len(tplsPath), func(i int) {
+		fileTpl, err := makeTemplateFileToCompileFromFile(tplsPath[i], t.Data, t.FuncsExport, t.HTML)
+		if err != nil {
+			fileErrs[i] = err
+			return
+		}
+		fileTpl.tplsDotType, fileTypeErrs[i] = typeCheckFile(fileTpl, t.Data, t.FuncsExport)
+		files[i] = fileTpl
+	})
+
+	typeErrs := []error{}
+	for i, err := range fileErrs {
 		if err != nil {
 			return err
 		}
-		t.files = append(t.files, fileTpl)
+		t.files = append(t.files, files[i])
+		typeErrs = append(typeErrs, fileTypeErrs[i]...)
+	}
+	if len(typeErrs) > 0 {
+		return &TypeCheckError{Errs: typeErrs}
 	}
 	return nil
 }
@@ -327,6 +450,7 @@ func makeTemplateFileToCompileFromFile(tplPath string, data interface{}, funcs m
 		tplsTree:         map[string]*parse.Tree{},
 		tplsFunc:         map[string]string{},
 		tplsTypeCheck:    map[string]*simplifier.State{},
+		tplsDotType:      map[string]reflect.Type{},
 		definedTemplates: []string{},
 	}
 
@@ -365,6 +489,7 @@ func makeTemplateFileToCompileFromStr(name, tplContent string, data interface{},
 		tplsTree:         map[string]*parse.Tree{},
 		tplsFunc:         map[string]string{},
 		tplsTypeCheck:    map[string]*simplifier.State{},
+		tplsDotType:      map[string]reflect.Type{},
 		definedTemplates: []string{},
 	}
 
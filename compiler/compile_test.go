@@ -0,0 +1,55 @@
+package compiler
+
+import (
+	"sync"
+	"testing"
+)
+
+// TestAddBuiltintTextNameIsOrderIndependent guards the "byte-identical
+// across runs" requirement: the same literal text must bind to the same
+// generated identifier no matter which parallel worker reaches it first.
+func TestAddBuiltintTextNameIsOrderIndependent(t *testing.T) {
+	first := NewCompiledTemplatesProgram("tpls")
+	nameFirst := first.addBuiltintText("<p>hello</p>")
+	first.addBuiltintText("<p>other</p>")
+
+	second := NewCompiledTemplatesProgram("tpls")
+	second.addBuiltintText("<p>other</p>")
+	nameSecond := second.addBuiltintText("<p>hello</p>")
+
+	if nameFirst != nameSecond {
+		t.Fatalf("expected the same text to bind to the same identifier regardless of discovery order, got %q and %q", nameFirst, nameSecond)
+	}
+}
+
+func TestAddBuiltintTextDiffersOnText(t *testing.T) {
+	c := NewCompiledTemplatesProgram("tpls")
+	a := c.addBuiltintText("<p>hello</p>")
+	b := c.addBuiltintText("<p>world</p>")
+	if a == b {
+		t.Fatal("expected two different literal texts to bind to different identifiers")
+	}
+}
+
+func TestAddBuiltintTextConcurrentCallersAgreeOnName(t *testing.T) {
+	c := NewCompiledTemplatesProgram("tpls")
+	const text = "<p>concurrent</p>"
+
+	names := make([]string, 20)
+	var wg sync.WaitGroup
+	wg.Add(len(names))
+	for i := range names {
+		i := i
+		go func() {
+			defer wg.Done()
+			names[i] = c.addBuiltintText(text)
+		}()
+	}
+	wg.Wait()
+
+	for _, name := range names {
+		if name != names[0] {
+			t.Fatalf("expected every concurrent caller to agree on the same identifier for the same text, got %q and %q", names[0], name)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package compiler
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template/parse"
+)
+
+// hashTree canonicalizes tree (the parse nodes, reconstructed as source via
+// Node.String, plus the dot type it was resolved against and the identity of
+// the funcs it would be compiled against) and returns a stable hash of it.
+// Two trees that hash the same render identically, so their generated body
+// func can be shared instead of duplicated. funcs must be folded in:
+// otherwise two configs sharing an identical partial but registering
+// different implementations under the same FuncsExport name (e.g. two
+// sites' own "T" translation helper) would collide onto the same generated
+// func and silently keep whichever config's func was seen first.
+func hashTree(tree *parse.Tree, dot reflect.Type, funcs map[string]interface{}) string {
+	canonical := ""
+	if tree != nil && tree.Root != nil {
+		canonical = tree.Root.String()
+	}
+	dotName := "<nil>"
+	if dot != nil {
+		dotName = dot.String()
+	}
+	sum := sha1.Sum([]byte(dotName + "\x00" + canonical + "\x00" + funcsIdentity(funcs)))
+	return hex.EncodeToString(sum[:])
+}
+
+// funcsIdentity returns a stable string identifying which concrete func
+// values are bound under each name in funcs, so two maps registering
+// different funcs under the same name don't compare equal. Each func is
+// identified by its code pointer, so two closures created from the same func
+// literal compare equal even when they capture different values.
+func funcsIdentity(funcs map[string]interface{}) string {
+	names := make([]string, 0, len(funcs))
+	for name := range funcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%v=%v", name, reflect.ValueOf(funcs[name]).Pointer())
+	}
+	return strings.Join(parts, "\x00")
+}
@@ -0,0 +1,73 @@
+package compiler
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHashTreeStableForIdenticalTreeAndDotType(t *testing.T) {
+	a := mustParseTree("a.html", "{{.Name}}")
+	b := mustParseTree("b.html", "{{.Name}}")
+	dot := reflect.TypeOf("")
+
+	if hashTree(a, dot, nil) != hashTree(b, dot, nil) {
+		t.Fatal("expected two distinct trees with identical structure and dot type to hash the same")
+	}
+}
+
+func TestHashTreeDiffersOnStructure(t *testing.T) {
+	a := mustParseTree("a.html", "{{.Name}}")
+	b := mustParseTree("b.html", "{{.Other}}")
+	dot := reflect.TypeOf("")
+
+	if hashTree(a, dot, nil) == hashTree(b, dot, nil) {
+		t.Fatal("expected trees with different pipelines to hash differently")
+	}
+}
+
+func TestHashTreeDiffersOnDotType(t *testing.T) {
+	a := mustParseTree("a.html", "{{.Name}}")
+
+	if hashTree(a, reflect.TypeOf(""), nil) == hashTree(a, reflect.TypeOf(0), nil) {
+		t.Fatal("expected the same tree resolved against two different dot types to hash differently")
+	}
+}
+
+func TestHashTreeHandlesNilTree(t *testing.T) {
+	if hashTree(nil, nil, nil) != hashTree(nil, nil, nil) {
+		t.Fatal("expected hashTree(nil, nil, nil) to be stable")
+	}
+}
+
+// TestHashTreeDiffersOnFuncsExportBinding guards the multi-config scenario:
+// two TemplateToCompile entries sharing byte-identical template text but
+// registering different implementations under the same FuncsExport name
+// (e.g. two sites' own "T" translation helper) must not collide onto the
+// same generated func.
+func TestHashTreeDiffersOnFuncsExportBinding(t *testing.T) {
+	a := mustParseTree("a.html", `{{T "greeting"}}`, map[string]interface{}{"T": func(string) string { return "" }})
+	dot := reflect.TypeOf("")
+
+	siteA := func(string) string { return "bonjour" }
+	siteB := func(string) string { return "hello" }
+
+	hashA := hashTree(a, dot, map[string]interface{}{"T": siteA})
+	hashB := hashTree(a, dot, map[string]interface{}{"T": siteB})
+	if hashA == hashB {
+		t.Fatal("expected two configs registering different funcs under the same name to hash differently")
+	}
+}
+
+func TestHashTreeStableForIdenticalFuncsExportBinding(t *testing.T) {
+	sig := map[string]interface{}{"T": func(string) string { return "" }}
+	a := mustParseTree("a.html", `{{T "greeting"}}`, sig)
+	b := mustParseTree("b.html", `{{T "greeting"}}`, sig)
+	dot := reflect.TypeOf("")
+	fn := func(string) string { return "hello" }
+
+	hashA := hashTree(a, dot, map[string]interface{}{"T": fn})
+	hashB := hashTree(b, dot, map[string]interface{}{"T": fn})
+	if hashA != hashB {
+		t.Fatal("expected identical trees registering the same func under the same name to hash the same")
+	}
+}
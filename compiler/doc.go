@@ -0,0 +1,27 @@
+// Package compiler generates Go source that implements parse.Templater for
+// a set of html/template or text/template files, so they can be compiled
+// into the final binary instead of parsed at startup.
+//
+// # Known limitations
+//
+// Lazy, execution-time resolution of FuncsExport funcs (so one compiled
+// binary could serve multiple configs that register different
+// implementations under the same func name, including closures) is
+// blocked: it needs convertTplTree, the function that emits codegen for
+// each call site, to fetch the func from a runtime FuncMap instead of
+// baking in a compile-time identifier — and convertTplTree isn't part of
+// this source tree. FuncsExport funcs are resolved at compile time: they are
+// passed to convertTplTree for code generation, and typeCheckFile checks
+// calls against their signatures. An attempt at the
+// init()-side plumbing was added and then removed in this series (see the
+// chunk0-2 request history) once it was clear it had no reachable call
+// site to wire into.
+//
+// Type-specialized print helpers (strconv.AppendInt/AppendUint/AppendFloat
+// and w.Write for numeric and []byte action results, instead of falling
+// back through fmt.Fprint) are blocked for the same reason: emitting them
+// means changing what convertTplTree generates for a print action, and
+// that function isn't part of this source tree either. A standalone
+// writer was added and then removed in this series (see the chunk0-6
+// request history) once it was clear it had no reachable call site.
+package compiler
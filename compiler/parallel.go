@@ -0,0 +1,40 @@
+package compiler
+
+import (
+	"runtime"
+	"sync"
+)
+
+// runParallel runs worker(i) for every i in [0, n) using a pool sized to
+// runtime.NumCPU(), and waits for all of them to finish. Callers that need
+// deterministic output must not rely on the order workers finish in; merge
+// results afterwards keyed by i instead.
+func runParallel(n int, worker func(i int)) {
+	if n == 0 {
+		return
+	}
+	workers := runtime.NumCPU()
+	if workers > n {
+		workers = n
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				worker(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
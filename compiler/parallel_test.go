@@ -0,0 +1,43 @@
+package compiler
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestRunParallelVisitsEveryIndexExactlyOnce(t *testing.T) {
+	const n = 37 // comfortably more than runtime.NumCPU() on any CI box
+	seen := make([]int, n)
+	var mu sync.Mutex
+
+	runParallel(n, func(i int) {
+		mu.Lock()
+		seen[i]++
+		mu.Unlock()
+	})
+
+	for i, count := range seen {
+		if count != 1 {
+			t.Fatalf("index %v was visited %v times, want exactly once", i, count)
+		}
+	}
+}
+
+func TestRunParallelHandlesZero(t *testing.T) {
+	runParallel(0, func(i int) {
+		t.Fatalf("worker should never be called for n == 0, got i=%v", i)
+	})
+}
+
+func TestRunParallelHandlesOne(t *testing.T) {
+	calls := 0
+	runParallel(1, func(i int) {
+		calls++
+		if i != 0 {
+			t.Fatalf("expected the single job to be index 0, got %v", i)
+		}
+	})
+	if calls != 1 {
+		t.Fatalf("expected exactly one call, got %v", calls)
+	}
+}
@@ -0,0 +1,350 @@
+package compiler
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template/parse"
+)
+
+// TypeError reports a single pipeline that cannot be resolved against the
+// declared data type: a missing field, a method that doesn't return one
+// value (or a value and an error), a registered func called with the wrong
+// number of arguments, or a func argument that isn't assignable to its
+// registered parameter type. The number of arguments a method takes is not
+// checked.
+type TypeError struct {
+	Location string // "file:line:col", as reported by parse.Tree.ErrorContext
+	Tree     string
+	Err      error
+}
+
+func (e *TypeError) Error() string {
+	return fmt.Sprintf("%v: in %q: %v", e.Location, e.Tree, e.Err)
+}
+
+// TypeCheckError aggregates every TypeError found while type-checking the
+// template files of one TemplateConfiguration. All trees are checked in one pass, so CompileAndWrite
+// fails loudly with every mismatch at once instead of stopping at the
+// first one.
+type TypeCheckError struct {
+	Errs []error
+}
+
+func (e *TypeCheckError) Error() string {
+	lines := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		lines[i] = err.Error()
+	}
+	return fmt.Sprintf("template type checking failed:\n%v", strings.Join(lines, "\n"))
+}
+
+// builtinFuncs lists the text/template builtins that take untyped or
+// variadic arguments and are not worth resolving against a func signature.
+var builtinFuncs = map[string]bool{
+	"and": true, "or": true, "not": true, "len": true, "index": true,
+	"print": true, "printf": true, "println": true,
+	"html": true, "js": true, "urlquery": true,
+	"eq": true, "ne": true, "lt": true, "le": true, "gt": true, "ge": true,
+	"call": true, "slice": true,
+}
+
+// typeCheckFile statically resolves every field/method/index access and
+// every registered func call in f against data and funcs, reporting all
+// errors it finds. f.name (the file's own tree) is the entry point and is
+// always checked against the root data type; traversing its {{template}}
+// calls propagates the actual pipe type into each callee before it is
+// checked, so a sub-template is validated under the type it's really
+// invoked with rather than always the file's root type. A sub-template
+// invoked from more than one call site with different dot types is checked
+// once per distinct type it's actually called with — each call site can
+// fail independently, instead of the first one checked suppressing the
+// rest. Any named template the traversal never reaches (e.g. a partial
+// only ever invoked directly from Go by name) still gets checked, falling
+// back to the root type since that's what it would receive as its own
+// entry point.
+func typeCheckFile(f TemplateFileToCompile, data interface{}, funcs map[string]interface{}) (map[string]reflect.Type, []error) {
+	rootType := reflect.TypeOf(data)
+
+	c := &typeChecker{
+		file:     f.name,
+		trees:    f.tplsTree,
+		funcs:    funcs,
+		dotTypes: map[string]reflect.Type{},
+		checked:  map[string]map[string]bool{},
+	}
+
+	c.checkTree(f.name, rootType)
+
+	for _, name := range f.names() {
+		if len(c.checked[name]) == 0 {
+			c.checkTree(name, rootType)
+		}
+	}
+	return c.dotTypes, c.errs
+}
+
+type typeChecker struct {
+	file     string
+	trees    map[string]*parse.Tree
+	funcs    map[string]interface{}
+	dotTypes map[string]reflect.Type    // one representative dot type per name, used by callers that generate one func per name
+	checked  map[string]map[string]bool // name -> set of dot types (by String(), "<nil>" for untyped) already checked against it
+	errs     []error
+}
+
+// checkTree checks tree name against dot, unless that exact (name, dot)
+// combination was already checked — which both skips redundant work when
+// the same call site is reached twice and stops recursive/cyclic
+// {{template}} chains from looping forever.
+func (c *typeChecker) checkTree(name string, dot reflect.Type) {
+	key := dotTypeKey(dot)
+	if c.checked[name] == nil {
+		c.checked[name] = map[string]bool{}
+	}
+	if c.checked[name][key] {
+		return
+	}
+	c.checked[name][key] = true
+
+	if _, ok := c.dotTypes[name]; !ok {
+		c.dotTypes[name] = dot
+	}
+
+	tree, ok := c.trees[name]
+	if !ok || tree == nil || tree.Root == nil {
+		return
+	}
+	c.checkList(name, tree.Root, dot, map[string]reflect.Type{})
+}
+
+func dotTypeKey(t reflect.Type) string {
+	if t == nil {
+		return "<nil>"
+	}
+	return t.String()
+}
+
+func (c *typeChecker) fail(tree string, n parse.Node, err error) {
+	location := tree
+	if t, ok := c.trees[tree]; ok && t != nil {
+		if loc, _ := t.ErrorContext(n); loc != "" {
+			location = loc
+		}
+	}
+	c.errs = append(c.errs, &TypeError{Location: location, Tree: tree, Err: err})
+}
+
+func (c *typeChecker) checkList(tree string, list *parse.ListNode, dot reflect.Type, vars map[string]reflect.Type) {
+	if list == nil {
+		return
+	}
+	for _, n := range list.Nodes {
+		c.checkNode(tree, n, dot, vars)
+	}
+}
+
+func (c *typeChecker) checkNode(tree string, n parse.Node, dot reflect.Type, vars map[string]reflect.Type) {
+	switch x := n.(type) {
+	case *parse.ActionNode:
+		c.resolvePipe(tree, x.Pipe, dot, vars)
+
+	case *parse.IfNode:
+		c.resolvePipe(tree, x.Pipe, dot, vars)
+		c.checkList(tree, x.List, dot, cloneVars(vars))
+		c.checkList(tree, x.ElseList, dot, cloneVars(vars))
+
+	case *parse.WithNode:
+		result := c.resolvePipe(tree, x.Pipe, dot, vars)
+		c.checkList(tree, x.List, result, cloneVars(vars))
+		c.checkList(tree, x.ElseList, dot, cloneVars(vars))
+
+	case *parse.RangeNode:
+		result := c.resolvePipe(tree, x.Pipe, dot, vars)
+		c.checkList(tree, x.List, elemType(result), cloneVars(vars))
+		c.checkList(tree, x.ElseList, dot, cloneVars(vars))
+
+	case *parse.TemplateNode:
+		calleeDot := dot
+		if x.Pipe != nil {
+			calleeDot = c.resolvePipe(tree, x.Pipe, dot, vars)
+		}
+		c.checkTree(x.Name, calleeDot)
+	}
+}
+
+func elemType(t reflect.Type) reflect.Type {
+	if t == nil {
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return t.Elem()
+	}
+	return t
+}
+
+func cloneVars(vars map[string]reflect.Type) map[string]reflect.Type {
+	ret := make(map[string]reflect.Type, len(vars))
+	for k, v := range vars {
+		ret[k] = v
+	}
+	return ret
+}
+
+// resolvePipe resolves the final command of pipe against dot, recording any
+// variable it declares ($x := ...) into vars for nodes checked afterwards.
+func (c *typeChecker) resolvePipe(tree string, pipe *parse.PipeNode, dot reflect.Type, vars map[string]reflect.Type) reflect.Type {
+	if pipe == nil {
+		return dot
+	}
+	var result reflect.Type
+	for _, cmd := range pipe.Cmds {
+		result = c.resolveCommand(tree, cmd, dot, vars)
+	}
+	for _, decl := range pipe.Decl {
+		if len(decl.Ident) > 0 {
+			vars[decl.Ident[0]] = result
+		}
+	}
+	return result
+}
+
+func (c *typeChecker) resolveCommand(tree string, cmd *parse.CommandNode, dot reflect.Type, vars map[string]reflect.Type) reflect.Type {
+	if len(cmd.Args) == 0 {
+		return dot
+	}
+	if ident, ok := cmd.Args[0].(*parse.IdentifierNode); ok {
+		return c.resolveFuncCall(tree, ident.Ident, cmd.Args[0], cmd.Args[1:], dot, vars)
+	}
+	return c.resolveArg(tree, cmd.Args[0], dot, vars)
+}
+
+func (c *typeChecker) resolveArg(tree string, n parse.Node, dot reflect.Type, vars map[string]reflect.Type) reflect.Type {
+	switch x := n.(type) {
+	case *parse.DotNode:
+		return dot
+	case *parse.FieldNode:
+		return c.resolveChain(tree, dot, x.Ident, x)
+	case *parse.ChainNode:
+		base := c.resolveArg(tree, x.Node, dot, vars)
+		return c.resolveChain(tree, base, x.Field, x)
+	case *parse.VariableNode:
+		base, ok := vars[x.Ident[0]]
+		if !ok {
+			return nil
+		}
+		if len(x.Ident) > 1 {
+			return c.resolveChain(tree, base, x.Ident[1:], x)
+		}
+		return base
+	case *parse.PipeNode:
+		return c.resolvePipe(tree, x, dot, vars)
+	case *parse.NumberNode:
+		if x.IsInt {
+			return reflect.TypeOf(int(0))
+		}
+		if x.IsFloat {
+			return reflect.TypeOf(float64(0))
+		}
+		return nil
+	case *parse.StringNode:
+		return reflect.TypeOf("")
+	case *parse.BoolNode:
+		return reflect.TypeOf(true)
+	case *parse.IdentifierNode:
+		return c.resolveFuncCall(tree, x.Ident, x, nil, dot, vars)
+	}
+	return nil
+}
+
+// resolveChain walks idents as a field/method access chain starting at base,
+// dereferencing pointers and returning the type at the end of the chain, or
+// nil (after recording a TypeError) if any segment cannot be resolved.
+func (c *typeChecker) resolveChain(tree string, base reflect.Type, idents []string, n parse.Node) reflect.Type {
+	cur := base
+	for _, name := range idents {
+		if cur == nil {
+			return nil
+		}
+		for cur.Kind() == reflect.Ptr {
+			cur = cur.Elem()
+		}
+		if m, ok := cur.MethodByName(name); ok {
+			if m.Type.NumOut() == 0 {
+				c.fail(tree, n, fmt.Errorf("method %q on %v does not return a value", name, base))
+				return nil
+			}
+			if m.Type.NumOut() > 2 || (m.Type.NumOut() == 2 && m.Type.Out(1) != reflect.TypeOf((*error)(nil)).Elem()) {
+				c.fail(tree, n, fmt.Errorf("method %q on %v must return one value, or a value and an error", name, base))
+				return nil
+			}
+			cur = m.Type.Out(0)
+			continue
+		}
+		if cur.Kind() != reflect.Struct {
+			c.fail(tree, n, fmt.Errorf("can't evaluate field %q on type %v", name, cur))
+			return nil
+		}
+		field, ok := cur.FieldByName(name)
+		if !ok {
+			c.fail(tree, n, fmt.Errorf("type %v has no field or method %q", cur, name))
+			return nil
+		}
+		cur = field.Type
+	}
+	return cur
+}
+
+// resolveFuncCall resolves a registered FuncsExport entry by name, checks the
+// arity and assignability of args against it, and returns its first result
+// type. Builtins are left unchecked beyond resolving their own arguments.
+func (c *typeChecker) resolveFuncCall(tree, name string, n parse.Node, args []parse.Node, dot reflect.Type, vars map[string]reflect.Type) reflect.Type {
+	if builtinFuncs[name] {
+		for _, a := range args {
+			c.resolveArg(tree, a, dot, vars)
+		}
+		return nil
+	}
+
+	fn, ok := c.funcs[name]
+	if !ok {
+		c.fail(tree, n, fmt.Errorf("function %q is not registered in FuncsExport", name))
+		return nil
+	}
+	fnType := reflect.TypeOf(fn)
+	if fnType == nil || fnType.Kind() != reflect.Func {
+		c.fail(tree, n, fmt.Errorf("%q is not a function", name))
+		return nil
+	}
+	if !fnType.IsVariadic() && len(args) != fnType.NumIn() {
+		c.fail(tree, n, fmt.Errorf("function %q wants %v argument(s), got %v", name, fnType.NumIn(), len(args)))
+	}
+
+	lastIn := fnType.NumIn() - 1
+	for i, a := range args {
+		argType := c.resolveArg(tree, a, dot, vars)
+		if argType == nil {
+			continue
+		}
+		idx := i
+		if idx > lastIn {
+			idx = lastIn
+		}
+		if idx < 0 {
+			continue
+		}
+		paramType := fnType.In(idx)
+		if fnType.IsVariadic() && idx == lastIn {
+			paramType = paramType.Elem()
+		}
+		if !argType.AssignableTo(paramType) {
+			c.fail(tree, a, fmt.Errorf("argument %v to %q is %v, not assignable to %v", i+1, name, argType, paramType))
+		}
+	}
+
+	if fnType.NumOut() == 0 {
+		return nil
+	}
+	return fnType.Out(0)
+}
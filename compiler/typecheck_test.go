@@ -0,0 +1,111 @@
+package compiler
+
+import (
+	"io/ioutil"
+	"testing"
+	text "text/template"
+	"text/template/parse"
+)
+
+type typeCheckOuter struct {
+	Inner typeCheckInner
+	Bad   typeCheckBad
+}
+
+type typeCheckInner struct {
+	Name string
+}
+
+type typeCheckBad struct{}
+
+func parseFileForTypeCheck(t *testing.T, name, content string, funcs map[string]interface{}) TemplateFileToCompile {
+	t.Helper()
+	tpl, err := text.New(name).Funcs(text.FuncMap(funcs)).Parse(content)
+	if err != nil {
+		t.Fatalf("parse %q: %v", content, err)
+	}
+
+	f := TemplateFileToCompile{name: name, tplsTree: map[string]*parse.Tree{}}
+	for _, tt := range tpl.Templates() {
+		tt.Execute(ioutil.Discard, nil) // force parse, same as makeTemplateFileToCompileFromFile
+		if tt.Tree != nil {
+			f.tplsTree[tt.Name()] = tt.Tree
+		}
+	}
+	return f
+}
+
+func TestTypeCheckFilePropagatesDotIntoSubTemplate(t *testing.T) {
+	f := parseFileForTypeCheck(t, "main.html",
+		`{{define "sub"}}{{.Name}}{{end}}{{template "sub" .Inner}}`, nil)
+
+	_, errs := typeCheckFile(f, typeCheckOuter{}, nil)
+	if len(errs) != 0 {
+		t.Fatalf("expected no errors once .Inner's type is propagated into \"sub\", got %v", errs)
+	}
+}
+
+func TestTypeCheckFileCatchesMismatchInPropagatedSubTemplate(t *testing.T) {
+	f := parseFileForTypeCheck(t, "main.html",
+		`{{define "sub"}}{{.DoesNotExist}}{{end}}{{template "sub" .Inner}}`, nil)
+
+	_, errs := typeCheckFile(f, typeCheckOuter{}, nil)
+	if len(errs) == 0 {
+		t.Fatal("expected an error for a field missing on the propagated .Inner type")
+	}
+}
+
+func TestTypeCheckFileCatchesMissingFieldOnRoot(t *testing.T) {
+	f := parseFileForTypeCheck(t, "main.html", `{{.DoesNotExist}}`, nil)
+
+	_, errs := typeCheckFile(f, typeCheckOuter{}, nil)
+	if len(errs) == 0 {
+		t.Fatal("expected an error for a field missing on the root data type")
+	}
+}
+
+func TestTypeCheckFileCatchesFuncArgumentMismatch(t *testing.T) {
+	funcs := map[string]interface{}{
+		"upper": func(s string) string { return s },
+	}
+	f := parseFileForTypeCheck(t, "main.html", `{{upper .Inner}}`, funcs)
+
+	_, errs := typeCheckFile(f, typeCheckOuter{}, funcs)
+	if len(errs) == 0 {
+		t.Fatal("expected an error: upper(string) called with a struct argument")
+	}
+}
+
+func TestTypeCheckFileCatchesWrongArity(t *testing.T) {
+	funcs := map[string]interface{}{
+		"upper": func(s string) string { return s },
+	}
+	f := parseFileForTypeCheck(t, "main.html", `{{upper .Inner.Name "extra"}}`, funcs)
+
+	_, errs := typeCheckFile(f, typeCheckOuter{}, funcs)
+	if len(errs) == 0 {
+		t.Fatal("expected an error: upper takes one argument, got two")
+	}
+}
+
+func TestTypeCheckFileChecksEachTemplateCallSiteIndependently(t *testing.T) {
+	f := parseFileForTypeCheck(t, "main.html",
+		`{{define "sub"}}{{.Name}}{{end}}{{template "sub" .Inner}}{{template "sub" .Bad}}`, nil)
+
+	_, errs := typeCheckFile(f, typeCheckOuter{}, nil)
+	if len(errs) == 0 {
+		t.Fatal("expected an error: the second {{template}} call site passes .Bad, which has no Name field, and must be checked even though the first call site (.Inner) already checked out")
+	}
+}
+
+func TestTypeCheckFileUnreachedTemplateFallsBackToRootType(t *testing.T) {
+	f := parseFileForTypeCheck(t, "main.html", `{{define "unused"}}{{.Inner.Name}}{{end}}`, nil)
+
+	dotTypes, errs := typeCheckFile(f, typeCheckOuter{}, nil)
+	if len(errs) != 0 {
+		t.Fatalf("expected no errors, got %v", errs)
+	}
+	if dotTypes["unused"] == nil {
+		t.Fatal("expected a fallback dot type for a template never reached via {{template}}")
+	}
+}